@@ -0,0 +1,84 @@
+package eca_recommend
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func writeTempFile(t *testing.T, data []byte) *os.File {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "sample-*.bin")
+	if err != nil {
+		t.Fatalf("CreateTemp: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	if _, err := f.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	return f
+}
+
+func TestEstimateCompressibility_ConstantByteData(t *testing.T) {
+	// An all-zero file is the flagship case this probe exists to catch: zero entropy,
+	// extremely compressible. It must not be treated as "no sample obtained".
+	data := bytes.Repeat([]byte{0x00}, 500*1024)
+	f := writeTempFile(t, data)
+
+	entropy, trialRatio, err := EstimateCompressibility(f, 0)
+	if err != nil {
+		t.Fatalf("EstimateCompressibility: %v", err)
+	}
+	if entropy != 0 {
+		t.Fatalf("entropy = %v, want 0 for constant-byte data", entropy)
+	}
+	if trialRatio >= 0.1 {
+		t.Fatalf("trialRatio = %v, want a small ratio for highly compressible data", trialRatio)
+	}
+}
+
+func TestEstimateCompressibility_RandomData(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	data := make([]byte, 256*1024)
+	rng.Read(data)
+	f := writeTempFile(t, data)
+
+	entropy, trialRatio, err := EstimateCompressibility(f, 0)
+	if err != nil {
+		t.Fatalf("EstimateCompressibility: %v", err)
+	}
+	if entropy < 7.5 {
+		t.Fatalf("entropy = %v, want close to 8 bits/byte for random data", entropy)
+	}
+	if trialRatio < 0.9 {
+		t.Fatalf("trialRatio = %v, want close to 1 for incompressible random data", trialRatio)
+	}
+}
+
+func TestEstimateCompressibility_EmptyFile(t *testing.T) {
+	f := writeTempFile(t, nil)
+	entropy, trialRatio, err := EstimateCompressibility(f, 0)
+	if err != nil {
+		t.Fatalf("EstimateCompressibility on empty file: %v", err)
+	}
+	if entropy != 0 || trialRatio != 0 {
+		t.Fatalf("entropy/trialRatio = %v/%v, want 0/0 for an empty file", entropy, trialRatio)
+	}
+}
+
+func TestRecommendAlgorithms_RecordsZeroEntropySample(t *testing.T) {
+	data := bytes.Repeat([]byte{0x00}, 500*1024)
+	f := writeTempFile(t, data)
+
+	rec, err := RecommendAlgorithms(f, 0, 0.5, int64(len(data)), Prefs{})
+	if err != nil {
+		t.Fatalf("RecommendAlgorithms: %v", err)
+	}
+	if _, ok := rec.ScoreBreakdown["entropy"]; !ok {
+		t.Fatalf("ScoreBreakdown missing \"entropy\" for a legitimately zero-entropy sample")
+	}
+	if _, ok := rec.ScoreBreakdown["trial_ratio"]; !ok {
+		t.Fatalf("ScoreBreakdown missing \"trial_ratio\" for a legitimately zero-entropy sample")
+	}
+}
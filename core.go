@@ -2,13 +2,11 @@ package eca_recommend
 
 import (
 	"fmt"
-	"io"
 	"math"
-	"mime"
-	"net/http"
 	"os"
-	"path/filepath"
 	"strings"
+
+	"github.com/PangolinLab/eca-recommend/integrity"
 )
 
 // Prefs 可选偏好："speed","ratio","balanced"
@@ -16,6 +14,13 @@ type Prefs struct {
 	Tradeoff       string // "speed"|"ratio"|"balanced"
 	AssumeAESNI    bool   // 是否假设有 AES-NI（会偏向 aes-gcm）
 	ForceDetectExt bool   // 是否强制使用扩展名优先（默认用扩展名+MIME）
+
+	// DisableCompressibilityProbe 跳过 EstimateCompressibility 采样探测，只按 MIME 分类判断。
+	// 探测本身会读取并试压缩文件，对极大量小文件批处理的场景可能不划算。
+	DisableCompressibilityProbe bool
+	SampleBytes                 int64   // 探测采样总字节数，默认 256 KiB
+	EntropySkipThreshold        float64 // 熵高于此值（bits/byte）强制 SkipCompression，默认 7.8
+	RatioSkipThreshold          float64 // 试压缩比高于此值强制 SkipCompression，默认 0.97
 }
 
 // Recommendation 推荐结果
@@ -28,6 +33,13 @@ type Recommendation struct {
 	Reason           string
 	DetectedMime     string
 	DetectedCategory string // "text","image","audio","video","archive","binary"
+
+	Integrity integrity.IntegrityPlan // 纠删码/完整性建议，仅供参考，不做实际编码
+
+	// ContentEncoding 和 VaryHeader 仅由 RecommendForHTTP 填充，对应 HTTP 响应头
+	// Content-Encoding 和 Vary 该填的值；RecommendAlgorithms 不会设置它们。
+	ContentEncoding string
+	VaryHeader      string
 }
 
 // RecommendAlgorithms 主推荐函数
@@ -123,15 +135,35 @@ func RecommendAlgorithms(file *os.File, lastUsedHours int, attention float64, si
 	// ---------- 压缩算法评分 ----------
 	compCandidates := []string{"zip", "lzma2", "lz4", "zstd"}
 	compScores := map[string]float64{"zip": 0, "lzma2": 0, "lz4": 0, "zstd": 0}
-	isAlreadyCompressed := false
-	switch category {
-	case "image", "video", "audio", "archive":
-		isAlreadyCompressed = true
+	alreadyCompressed := isAlreadyCompressed(mimeStr, category)
+
+	// ---------- 压缩性探测（熵 + 试压缩）----------
+	entropySkipThreshold := prefs.EntropySkipThreshold
+	if entropySkipThreshold <= 0 {
+		entropySkipThreshold = 7.8
+	}
+	ratioSkipThreshold := prefs.RatioSkipThreshold
+	if ratioSkipThreshold <= 0 {
+		ratioSkipThreshold = 0.97
+	}
+	forceSkipByProbe := false
+	lowRatioBoost := 0.0 // 0 表示不加权；否则为 (0.55 - trialRatio) 的加权系数
+	if !prefs.DisableCompressibilityProbe && sizeBytes > 0 {
+		if entropy, trialRatio, perr := EstimateCompressibility(file, prefs.SampleBytes); perr == nil {
+			rec.ScoreBreakdown["entropy"] = entropy
+			rec.ScoreBreakdown["trial_ratio"] = trialRatio
+			if entropy > entropySkipThreshold || trialRatio > ratioSkipThreshold {
+				forceSkipByProbe = true
+			}
+			if trialRatio > 0 && trialRatio < 0.55 {
+				lowRatioBoost = 0.55 - trialRatio
+			}
+		}
 	}
 
 	for _, c := range compCandidates {
 		score := 0.1
-		if isAlreadyCompressed {
+		if alreadyCompressed {
 			if c == "lz4" {
 				score += 2.0
 			} else {
@@ -183,6 +215,10 @@ func RecommendAlgorithms(file *os.File, lastUsedHours int, attention float64, si
 		if sizeMB > 500 && (c == "zstd" || c == "lz4") {
 			score += 1.2
 		}
+		if lowRatioBoost > 0 && (c == "lzma2" || c == "zstd") {
+			// 试压缩比越低说明样本越"可压"，按 (0.55 - ratio) 的幅度加权到高压比算法上。
+			score += lowRatioBoost * 4.0
+		}
 		compScores[c] = score
 		rec.ScoreBreakdown["comp_"+c] = score
 	}
@@ -199,8 +235,12 @@ func RecommendAlgorithms(file *os.File, lastUsedHours int, attention float64, si
 		bestComp = "lz4"
 	}
 
-	// SkipCompression 逻辑
-	if isAlreadyCompressed && (bestCompScore < 0.5 || (bestComp == "lz4" && tradeoff != "ratio")) {
+	// SkipCompression 逻辑：MIME 分类建议跳过，或探测信号（高熵/高试压缩比）强制跳过。
+	if forceSkipByProbe {
+		rec.SkipCompression = true
+		rec.Compression = "none"
+		rec.Reason = fmt.Sprintf("压缩性探测显示该文件几乎不可压缩（熵=%.2f bits/byte，试压缩比=%.2f），建议跳过压缩。", rec.ScoreBreakdown["entropy"], rec.ScoreBreakdown["trial_ratio"])
+	} else if alreadyCompressed && (bestCompScore < 0.5 || (bestComp == "lz4" && tradeoff != "ratio")) {
 		rec.SkipCompression = true
 		rec.Compression = "none"
 		rec.Reason = fmt.Sprintf("文件类型 %s 可能已经被压缩，建议跳过压缩。", category)
@@ -235,6 +275,10 @@ func RecommendAlgorithms(file *os.File, lastUsedHours int, attention float64, si
 		default:
 			level = 3
 		}
+		if lowRatioBoost > 0 {
+			// 样本仍然很"可压"，值得多花一点 CPU 换取更好的比率。
+			level += 2
+		}
 		if level < 1 {
 			level = 1
 		}
@@ -244,45 +288,8 @@ func RecommendAlgorithms(file *os.File, lastUsedHours int, attention float64, si
 		rec.ZstdLevel = level
 	}
 
-	return rec, nil
-}
-
-// detectFileCategory returns mime and coarse category
-func detectFileCategory(f *os.File) (string, string) {
-	buf := make([]byte, 512)
-	n, err := f.ReadAt(buf, 0)
-	if err != nil && err != io.EOF {
-		return "application/octet-stream", "binary"
-	}
-	sniff := buf[:n]
-	mimeStr := http.DetectContentType(sniff)
-
-	ext := strings.ToLower(filepath.Ext(f.Name()))
-	if ext != "" {
-		if m := mime.TypeByExtension(ext); m != "" {
-			mimeStr = m
-		}
-	}
+	// ---------- 完整性/纠删码建议 ----------
+	rec.Integrity = integrity.Plan(lastUsedHours, attention, sizeBytes)
 
-	low := strings.ToLower(mimeStr)
-	category := "binary"
-	if strings.HasPrefix(low, "text/") {
-		category = "text"
-	} else if strings.HasPrefix(low, "image/") {
-		category = "image"
-	} else if strings.HasPrefix(low, "audio/") {
-		category = "audio"
-	} else if strings.HasPrefix(low, "video/") {
-		category = "video"
-	} else if strings.Contains(low, "zip") || strings.Contains(low, "compressed") || strings.Contains(low, "x-rar") || strings.Contains(low, "7z") || strings.Contains(low, "tar") {
-		category = "archive"
-	} else {
-		switch ext {
-		case ".txt", ".md", ".csv", ".log", ".json", ".xml", ".yaml", ".yml", ".go", ".py", ".c", ".cpp", ".java":
-			category = "text"
-		case ".pdf":
-			category = "archive"
-		}
-	}
-	return mimeStr, category
+	return rec, nil
 }
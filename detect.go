@@ -0,0 +1,412 @@
+package eca_recommend
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Matcher 是检测树中的一个节点：判断 header 是否匹配，匹配时给出 mime/ext。
+// 子节点只有在父节点匹配后才会被尝试，因此越具体的类型应该挂在越深的层级。
+//
+// Check 只看得到一段 header 字节，足够应付签名位于文件开头的绝大多数格式。
+// 但有些格式的关键结构（比如 zip 的中央目录）就算文件很大也固定在文件末尾，
+// 这类 Matcher 应该设置 CheckFile：有文件句柄可用时优先调用它，传入的是
+// 真实文件和其大小，可以用 ReaderAt 直接跳到文件任意位置。
+type Matcher struct {
+	Mime      string
+	Ext       string
+	Category  string
+	Check     func(header []byte) bool
+	CheckFile func(f *os.File, size int64) bool
+	children  []*Matcher
+}
+
+// Detector 是一棵按"超类型 -> 具体类型"组织的签名匹配树。
+type Detector struct {
+	root *Matcher
+}
+
+// root 是一个永远匹配的哨兵节点，真正的顶层类型（archive/image/...）挂在它下面。
+func newRoot() *Matcher {
+	return &Matcher{
+		Mime:     "application/octet-stream",
+		Category: "binary",
+		Check:    func(header []byte) bool { return true },
+	}
+}
+
+// defaultDetector 是内置签名树，覆盖 stdlib http.DetectContentType 遗漏的主要压缩/容器格式。
+var defaultDetector = newDefaultDetector()
+
+func newDefaultDetector() *Detector {
+	d := &Detector{root: newRoot()}
+
+	// ---------- 压缩/归档类 ----------
+	d.RegisterMatcher("", &Matcher{
+		Mime: "application/x-7z-compressed", Ext: ".7z", Category: "archive",
+		Check: hasPrefix(0x37, 0x7a, 0xbc, 0xaf, 0x27, 0x1c),
+	})
+	d.RegisterMatcher("", &Matcher{
+		Mime: "application/x-rar-compressed", Ext: ".rar", Category: "archive",
+		Check: hasPrefix(0x52, 0x61, 0x72, 0x21, 0x1a, 0x07),
+	})
+	d.RegisterMatcher("", &Matcher{
+		Mime: "application/x-xz", Ext: ".xz", Category: "archive",
+		Check: hasPrefix(0xfd, 0x37, 0x7a, 0x58, 0x5a, 0x00),
+	})
+	d.RegisterMatcher("", &Matcher{
+		Mime: "application/x-bzip2", Ext: ".bz2", Category: "archive",
+		Check: hasPrefix(0x42, 0x5a, 0x68),
+	})
+	d.RegisterMatcher("", &Matcher{
+		Mime: "application/gzip", Ext: ".gz", Category: "archive",
+		Check: hasPrefix(0x1f, 0x8b),
+	})
+	d.RegisterMatcher("", &Matcher{
+		Mime: "application/zstd", Ext: ".zst", Category: "archive",
+		Check: hasPrefix(0x28, 0xb5, 0x2f, 0xfd),
+	})
+	d.RegisterMatcher("", &Matcher{
+		Mime: "application/x-lz4", Ext: ".lz4", Category: "archive",
+		Check: hasPrefix(0x04, 0x22, 0x4d, 0x18),
+	})
+
+	// zip 家族是超类型：裸 zip、加密 zip、OOXML 都共享 "PK\x03\x04" 前缀，
+	// 具体类型由子节点进一步打开中央目录来区分。
+	zipSuper := &Matcher{
+		Mime: "application/zip", Ext: ".zip", Category: "archive",
+		Check: hasPrefix(0x50, 0x4b, 0x03, 0x04),
+	}
+	d.RegisterMatcher("", zipSuper)
+	d.RegisterMatcherUnder(zipSuper, &Matcher{
+		Mime: "application/vnd.openxmlformats-officedocument", Category: "archive",
+		// 中央目录通常在文件末尾，header-only 的 Check 只能兜底处理整个文件都在
+		// header 缓冲区内的小 zip；真实文件靠 CheckFile 用完整的 *os.File 定位。
+		Check:     isOOXMLHeader,
+		CheckFile: isOOXMLFile,
+	})
+	d.RegisterMatcherUnder(zipSuper, &Matcher{
+		Mime: "application/x-zip-encrypted", Category: "archive",
+		Check: isEncryptedZip,
+	})
+
+	d.RegisterMatcher("", &Matcher{
+		Mime: "application/vnd.debian.binary-package", Ext: ".deb", Category: "archive",
+		Check: hasPrefix(0x21, 0x3c, 0x61, 0x72, 0x63, 0x68, 0x3e), // "!<arch>"
+	})
+	d.RegisterMatcher("", &Matcher{
+		Mime: "application/vnd.ms-cab-compressed", Ext: ".cab", Category: "archive",
+		Check: hasPrefix(0x4d, 0x53, 0x43, 0x46), // "MSCF"
+	})
+	d.RegisterMatcher("", &Matcher{
+		Mime: "application/dicom", Ext: ".dcm", Category: "binary",
+		Check: isDICOM,
+	})
+	d.RegisterMatcher("", &Matcher{
+		Mime: "application/vnd.sqlite3", Ext: ".sqlite", Category: "binary",
+		Check: hasPrefix(0x53, 0x51, 0x4c, 0x69, 0x74, 0x65, 0x20, 0x66), // "SQLite f"
+	})
+	d.RegisterMatcher("", &Matcher{
+		Mime: "application/java-vm", Ext: ".class", Category: "binary",
+		Check: hasPrefix(0xca, 0xfe, 0xba, 0xbe),
+	})
+
+	// ---------- ISO-BMFF 容器（avif/heic）及其它图像/视频专有类型 ----------
+	d.RegisterMatcher("", &Matcher{
+		Mime: "image/avif", Ext: ".avif", Category: "image",
+		Check: isISOBMFFBrand("avif", "avis"),
+	})
+	d.RegisterMatcher("", &Matcher{
+		Mime: "image/heic", Ext: ".heic", Category: "image",
+		Check: isISOBMFFBrand("heic", "heix", "heim", "heis", "mif1"),
+	})
+	d.RegisterMatcher("", &Matcher{
+		Mime: "image/bpg", Ext: ".bpg", Category: "image",
+		Check: hasPrefix(0x42, 0x50, 0x47, 0xfb),
+	})
+	d.RegisterMatcher("", &Matcher{
+		Mime: "image/vnd.djvu", Ext: ".djvu", Category: "image",
+		Check: hasPrefix(0x41, 0x54, 0x26, 0x54, 0x46, 0x4f, 0x52, 0x4d), // "AT&TFORM"
+	})
+
+	// apng 是 png 的超类型之下的子节点：只有先匹配 png 签名，再找到 acTL 才算 apng。
+	pngSuper := &Matcher{
+		Mime: "image/png", Ext: ".png", Category: "image",
+		Check: hasPrefix(0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a),
+	}
+	d.RegisterMatcher("", pngSuper)
+	d.RegisterMatcherUnder(pngSuper, &Matcher{
+		Mime: "image/apng", Ext: ".apng", Category: "image",
+		Check: isAPNG,
+	})
+
+	return d
+}
+
+func hasPrefix(b ...byte) func([]byte) bool {
+	return func(header []byte) bool {
+		return len(header) >= len(b) && bytes.Equal(header[:len(b)], b)
+	}
+}
+
+// isOOXMLHeader 是 isOOXMLFile 在只有 header 字节、没有文件句柄时的兜底实现：
+// 只有当整个 zip（含中央目录）恰好落在 header 缓冲区内时才能命中，对任何
+// 有意义大小的 docx/xlsx/pptx 基本不会命中，真正的判断交给 isOOXMLFile。
+func isOOXMLHeader(header []byte) bool {
+	return scanOOXMLEntries(bytes.NewReader(header), int64(len(header)))
+}
+
+// isOOXMLFile 直接把 *os.File 连同其真实大小交给 zip.NewReader：
+// zip 包会用 ReaderAt 跳到文件末尾读取 End Of Central Directory 记录，
+// 所以中央目录在文件末尾这件事对它没有影响，文件多大都能正确定位。
+func isOOXMLFile(f *os.File, size int64) bool {
+	if f == nil || size <= 0 {
+		return false
+	}
+	return scanOOXMLEntries(f, size)
+}
+
+// scanOOXMLEntries 扫描 zip 中央目录，查找 "[Content_Types].xml" 来判断是否为 OOXML 文档。
+func scanOOXMLEntries(r io.ReaderAt, size int64) bool {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return false
+	}
+	for _, f := range zr.File {
+		if f.Name == "[Content_Types].xml" {
+			return true
+		}
+	}
+	return false
+}
+
+// isEncryptedZip 检查 zip 本地文件头中的通用标记位 bit 0（加密位）。
+func isEncryptedZip(header []byte) bool {
+	if len(header) < 8 {
+		return false
+	}
+	flags := uint16(header[6]) | uint16(header[7])<<8
+	return flags&0x1 != 0
+}
+
+// isDICOM 检查偏移 128 字节处的 "DICM" 魔数（前置 128 字节 preamble）。
+func isDICOM(header []byte) bool {
+	const preamble = 128
+	if len(header) < preamble+4 {
+		return false
+	}
+	return bytes.Equal(header[preamble:preamble+4], []byte("DICM"))
+}
+
+// isISOBMFFBrand 解析 ISO-BMFF 的 "ftyp" box，判断主/兼容 brand 是否在给定集合中。
+func isISOBMFFBrand(brands ...string) func([]byte) bool {
+	return func(header []byte) bool {
+		if len(header) < 12 || !bytes.Equal(header[4:8], []byte("ftyp")) {
+			return false
+		}
+		want := make(map[string]bool, len(brands))
+		for _, b := range brands {
+			want[b] = true
+		}
+		boxSize := int(uint32(header[0])<<24 | uint32(header[1])<<16 | uint32(header[2])<<8 | uint32(header[3]))
+		if boxSize < 16 || boxSize > len(header) {
+			boxSize = len(header)
+		}
+		// major brand 在 [8:12)，兼容 brand 列表从 16 开始，每 4 字节一个。
+		if want[string(header[8:12])] {
+			return true
+		}
+		for off := 16; off+4 <= boxSize; off += 4 {
+			if want[string(header[off:off+4])] {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// isAPNG 在 IDAT 之前查找 acTL chunk，区分动态 PNG 与普通 PNG。
+func isAPNG(header []byte) bool {
+	const pngSig = 8
+	pos := pngSig
+	for pos+8 <= len(header) {
+		length := int(uint32(header[pos])<<24 | uint32(header[pos+1])<<16 | uint32(header[pos+2])<<8 | uint32(header[pos+3]))
+		typ := string(header[pos+4 : pos+8])
+		if typ == "acTL" {
+			return true
+		}
+		if typ == "IDAT" {
+			return false
+		}
+		pos += 8 + length + 4 // length + type + data + crc
+	}
+	return false
+}
+
+// RegisterMatcher 把 m 挂在名为 parentMime 的节点下；parentMime 为空字符串时挂在根节点下
+// （即作为顶层类型）。调用方可以用这个接口扩展检测树，覆盖内置未识别的格式。
+func (d *Detector) RegisterMatcher(parentMime string, m *Matcher) {
+	parent := d.findNode(d.root, parentMime)
+	if parent == nil {
+		parent = d.root
+	}
+	parent.children = append(parent.children, m)
+}
+
+// RegisterMatcherUnder 与 RegisterMatcher 类似，但直接接受父节点指针，便于在构造期间
+// 把子节点挂在刚刚创建、尚未注册到树里的父节点上。
+func (d *Detector) RegisterMatcherUnder(parent *Matcher, m *Matcher) {
+	parent.children = append(parent.children, m)
+}
+
+func (d *Detector) findNode(n *Matcher, mimeStr string) *Matcher {
+	if mimeStr == "" {
+		return nil
+	}
+	if n.Mime == mimeStr {
+		return n
+	}
+	for _, c := range n.children {
+		if found := d.findNode(c, mimeStr); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Match 沿检测树深度优先下降，返回匹配路径中最深（最具体）节点的 mime/ext/category。
+// header 通常是文件开头的一段字节（建议至少 512 字节，容器格式可能需要更多以便扫描中央目录）。
+// 没有文件句柄时，带 CheckFile 的节点退回到它的 header-only Check（没有 Check 则按未匹配处理）；
+// 关键结构落在 header 之外时这类 Check 通常不会命中，结果停在父节点的粗分类。
+func (d *Detector) Match(header []byte) (mimeStr, ext, category string) {
+	return d.match(header, nil, 0)
+}
+
+// MatchFile 和 Match 一样沿检测树下降，但额外把 f 和 size 传给支持 CheckFile 的节点，
+// 让它们能在整份文件里定位签名（例如 zip 中央目录通常在文件末尾）。
+func (d *Detector) MatchFile(f *os.File, header []byte, size int64) (mimeStr, ext, category string) {
+	return d.match(header, f, size)
+}
+
+func (d *Detector) match(header []byte, f *os.File, size int64) (mimeStr, ext, category string) {
+	best := d.root
+	node := d.root
+	for {
+		matchedChild := (*Matcher)(nil)
+		for _, c := range node.children {
+			if c.matches(header, f, size) {
+				matchedChild = c
+				break
+			}
+		}
+		if matchedChild == nil {
+			break
+		}
+		best = matchedChild
+		node = matchedChild
+	}
+	return best.Mime, best.Ext, best.Category
+}
+
+// matches 优先使用 CheckFile（需要 f 非空），否则退回到 header-only 的 Check。
+func (m *Matcher) matches(header []byte, f *os.File, size int64) bool {
+	if m.CheckFile != nil && f != nil {
+		return m.CheckFile(f, size)
+	}
+	if m.Check != nil {
+		return m.Check(header)
+	}
+	return false
+}
+
+// isAlreadyCompressedTable 列出被视为"已压缩"的 mime，供 isAlreadyCompressed 使用；category 的判断在 isAlreadyCompressed 里。
+var isAlreadyCompressedTable = map[string]bool{
+	"application/x-7z-compressed":  true,
+	"application/x-rar-compressed": true,
+	"application/x-xz":             true,
+	"application/x-bzip2":          true,
+	"application/gzip":             true,
+	"application/zstd":             true,
+	"application/x-lz4":            true,
+	"application/x-zip-encrypted":  true,
+	"application/zip":              true,
+	"image/avif":                   true,
+	"image/heic":                   true,
+	"image/bpg":                    true,
+	"image/apng":                   true,
+	"image/png":                    true,
+	"image/vnd.djvu":               true,
+}
+
+// detectFileCategory 读取文件头部若干字节，用 Detector 树 + 扩展名兜底给出 mime 和粗分类。
+func detectFileCategory(f *os.File) (string, string) {
+	// 容器格式（zip 中央目录、avif/heic 的 ftyp brand 列表）可能需要比 512 字节更多的数据，
+	// 读 64 KiB 作为折中；位于文件末尾的 zip 中央目录不靠这段缓冲，而是由 MatchFile 把文件句柄交给 CheckFile 定位。
+	const sniffLen = 64 * 1024
+	buf := make([]byte, sniffLen)
+	n, err := f.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return "application/octet-stream", "binary"
+	}
+	sniff := buf[:n]
+
+	fileSize := int64(n)
+	if fi, ferr := f.Stat(); ferr == nil {
+		fileSize = fi.Size()
+	}
+	mimeStr, _, category := defaultDetector.MatchFile(f, sniff, fileSize)
+	if category == "binary" && mimeStr == "application/octet-stream" {
+		// 内置树没有命中任何已知签名，退回 stdlib 的启发式嗅探。
+		if stdMime := http.DetectContentType(sniff); stdMime != "application/octet-stream" {
+			mimeStr = stdMime
+		}
+	}
+
+	ext := strings.ToLower(filepath.Ext(f.Name()))
+	if ext != "" {
+		if m := mime.TypeByExtension(ext); m != "" && category == "binary" {
+			mimeStr = m
+		}
+	}
+
+	low := strings.ToLower(mimeStr)
+	if category == "binary" {
+		if strings.HasPrefix(low, "text/") {
+			category = "text"
+		} else if strings.HasPrefix(low, "image/") {
+			category = "image"
+		} else if strings.HasPrefix(low, "audio/") {
+			category = "audio"
+		} else if strings.HasPrefix(low, "video/") {
+			category = "video"
+		} else if strings.Contains(low, "zip") || strings.Contains(low, "compressed") || strings.Contains(low, "x-rar") || strings.Contains(low, "7z") || strings.Contains(low, "tar") {
+			category = "archive"
+		} else {
+			switch ext {
+			case ".txt", ".md", ".csv", ".log", ".json", ".xml", ".yaml", ".yml", ".go", ".py", ".c", ".cpp", ".java":
+				category = "text"
+			case ".pdf":
+				category = "archive"
+			}
+		}
+	}
+	return mimeStr, category
+}
+
+// isAlreadyCompressed 判断给定 mime/category 组合是否应当被当作"已经压缩过"处理。
+func isAlreadyCompressed(mimeStr, category string) bool {
+	if isAlreadyCompressedTable[strings.ToLower(mimeStr)] {
+		return true
+	}
+	switch category {
+	case "image", "video", "audio", "archive":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package eca_recommend
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"testing"
+)
+
+// writeZipWithEntries builds a zip (optionally OOXML-flavoured) file on disk and
+// returns the opened *os.File, positioned so ReadAt(0) works as detectFileCategory expects.
+func writeZipWithEntries(t *testing.T, entryNames []string, pad int) *os.File {
+	t.Helper()
+
+	f, err := os.CreateTemp(t.TempDir(), "zip-*.bin")
+	if err != nil {
+		t.Fatalf("CreateTemp: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	zw := zip.NewWriter(f)
+	for _, name := range entryNames {
+		// zip.Store instead of the zw.Create default (DEFLATE): padding with a
+		// repeated byte would otherwise compress down to a few bytes and never
+		// push the archive past the 64 KiB sniff window the test cares about.
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		if err != nil {
+			t.Fatalf("zw.CreateHeader(%q): %v", name, err)
+		}
+		if _, err := w.Write(bytes.Repeat([]byte{'a'}, pad)); err != nil {
+			t.Fatalf("write entry %q: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zw.Close: %v", err)
+	}
+	return f
+}
+
+func TestIsOOXMLFile_LargeArchive(t *testing.T) {
+	// Pad one entry so the central directory ends up well past the 64 KiB
+	// sniff window that detectFileCategory reads, mirroring a real multi-MiB docx/xlsx.
+	f := writeZipWithEntries(t, []string{"[Content_Types].xml", "word/document.xml"}, 200*1024)
+
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if fi.Size() <= 64*1024 {
+		t.Fatalf("test archive too small to exercise the tail-of-file case: %d bytes", fi.Size())
+	}
+
+	if !isOOXMLFile(f, fi.Size()) {
+		t.Fatalf("isOOXMLFile: expected true for a zip whose central directory is past the first 64 KiB")
+	}
+
+	mimeStr, _, category := defaultDetector.MatchFile(f, []byte{0x50, 0x4b, 0x03, 0x04}, fi.Size())
+	if mimeStr != "application/vnd.openxmlformats-officedocument" {
+		t.Fatalf("MatchFile mime = %q, want OOXML mime", mimeStr)
+	}
+	if category != "archive" {
+		t.Fatalf("MatchFile category = %q, want archive", category)
+	}
+}
+
+func TestIsOOXMLFile_PlainZipIsNotOOXML(t *testing.T) {
+	f := writeZipWithEntries(t, []string{"readme.txt"}, 1024)
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if isOOXMLFile(f, fi.Size()) {
+		t.Fatalf("isOOXMLFile: expected false for a zip without [Content_Types].xml")
+	}
+}
+
+func TestIsOOXMLFile_NilOrEmpty(t *testing.T) {
+	if isOOXMLFile(nil, 100) {
+		t.Fatalf("isOOXMLFile(nil, ...) should be false")
+	}
+	f, err := os.CreateTemp(t.TempDir(), "empty-*.bin")
+	if err != nil {
+		t.Fatalf("CreateTemp: %v", err)
+	}
+	defer f.Close()
+	if isOOXMLFile(f, 0) {
+		t.Fatalf("isOOXMLFile with size 0 should be false")
+	}
+}
+
+func isoBMFFHeader(majorBrand string, compatBrands ...string) []byte {
+	// box layout: size(4) "ftyp"(4) major_brand(4) minor_version(4) [compat_brand(4)]*
+	buf := make([]byte, 16+4*len(compatBrands))
+	size := uint32(len(buf))
+	buf[0] = byte(size >> 24)
+	buf[1] = byte(size >> 16)
+	buf[2] = byte(size >> 8)
+	buf[3] = byte(size)
+	copy(buf[4:8], "ftyp")
+	copy(buf[8:12], majorBrand)
+	// bytes [12:16) are minor_version, left zeroed
+	for i, b := range compatBrands {
+		copy(buf[16+4*i:20+4*i], b)
+	}
+	return buf
+}
+
+func TestIsISOBMFFBrand(t *testing.T) {
+	avifCheck := isISOBMFFBrand("avif", "avis")
+
+	if !avifCheck(isoBMFFHeader("avif")) {
+		t.Fatalf("expected avif major brand to match")
+	}
+	if !avifCheck(isoBMFFHeader("mif1", "avif")) {
+		t.Fatalf("expected avif compatible brand to match")
+	}
+	if avifCheck(isoBMFFHeader("heic")) {
+		t.Fatalf("heic major brand should not match an avif-only matcher")
+	}
+	if avifCheck([]byte{0x00, 0x01, 0x02}) {
+		t.Fatalf("short/garbage header should not match")
+	}
+}
+
+func pngChunk(typ string, data []byte) []byte {
+	length := uint32(len(data))
+	buf := make([]byte, 4+4+len(data)+4) // length + type + data + crc (crc left as zero, unchecked by isAPNG)
+	buf[0] = byte(length >> 24)
+	buf[1] = byte(length >> 16)
+	buf[2] = byte(length >> 8)
+	buf[3] = byte(length)
+	copy(buf[4:8], typ)
+	copy(buf[8:8+len(data)], data)
+	return buf
+}
+
+func pngSignature() []byte {
+	return []byte{0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a}
+}
+
+func TestIsAPNG(t *testing.T) {
+	var animated bytes.Buffer
+	animated.Write(pngSignature())
+	animated.Write(pngChunk("acTL", []byte{0, 0, 0, 1, 0, 0, 0, 0}))
+	animated.Write(pngChunk("IDAT", []byte{1, 2, 3}))
+	if !isAPNG(animated.Bytes()) {
+		t.Fatalf("expected apng (acTL before IDAT) to be detected")
+	}
+
+	var static bytes.Buffer
+	static.Write(pngSignature())
+	static.Write(pngChunk("IHDR", []byte{0, 0, 1, 0}))
+	static.Write(pngChunk("IDAT", []byte{1, 2, 3}))
+	if isAPNG(static.Bytes()) {
+		t.Fatalf("expected a plain png (no acTL before IDAT) not to be detected as apng")
+	}
+}
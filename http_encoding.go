@@ -0,0 +1,163 @@
+package eca_recommend
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Encoding 是解析后的单个 Accept-Encoding token 及其 q 值。
+type Encoding struct {
+	Name string
+	Q    float64
+}
+
+// httpCompCandidates 是 RecommendForHTTP 考虑的传输编码集合，识别度按 RFC 7231/RFC 7932 惯例。
+var httpCompCandidates = []string{"gzip", "deflate", "br", "zstd"}
+
+// ParseAcceptEncoding 解析形如 "gzip;q=0.8, br, *;q=0" 的 Accept-Encoding 头，
+// 返回按 q 值降序排列的 Encoding 列表；重复 token 只保留首次出现的 q 值，
+// "*;q=0" 会被保留在结果中（调用方用它判断"除列出项外一律拒绝"）。
+func ParseAcceptEncoding(h string) []Encoding {
+	h = strings.TrimSpace(h)
+	if h == "" {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var out []Encoding
+	for _, part := range strings.Split(h, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name := part
+		q := 1.0
+		if idx := strings.Index(part, ";"); idx >= 0 {
+			name = strings.TrimSpace(part[:idx])
+			params := part[idx+1:]
+			for _, p := range strings.Split(params, ";") {
+				p = strings.TrimSpace(p)
+				if strings.HasPrefix(p, "q=") {
+					if v, err := strconv.ParseFloat(strings.TrimSpace(p[2:]), 64); err == nil {
+						q = v
+					}
+				}
+			}
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, Encoding{Name: name, Q: q})
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Q > out[j].Q })
+	return out
+}
+
+// acceptsEncoding 判断 accepted（已按 ParseAcceptEncoding 解析）是否允许使用 name 编码，
+// 处理显式列出、通配符 "*" 以及 "identity" 的隐式允许规则。
+func acceptsEncoding(accepted []Encoding, name string) bool {
+	var wildcardQ = -1.0
+	for _, e := range accepted {
+		if e.Name == name {
+			return e.Q > 0
+		}
+		if e.Name == "*" {
+			wildcardQ = e.Q
+		}
+	}
+	if wildcardQ >= 0 {
+		return wildcardQ > 0
+	}
+	// 没有任何 Accept-Encoding 头或者没提到该编码也没有 "*"：除 identity 外一律视为不接受，
+	// identity 按 RFC 7231 §5.3.4 默认总是可接受，除非被显式 q=0 拒绝。
+	return name == "identity"
+}
+
+// RecommendForHTTP 根据客户端的 Accept-Encoding 头，在其声明接受的编码范围内挑选一个
+// 用于 HTTP 响应的传输编码。与 RecommendAlgorithms 的离线归档场景不同，这里只在
+// {gzip, deflate, br, zstd} 之间选择，并且图像/视频/音频/归档类内容一律不压缩
+// （它们几乎总是已经是压缩格式，二次压缩的 CPU 成本得不到回报）。
+func RecommendForHTTP(file *os.File, acceptEncoding string, prefs Prefs) (Recommendation, error) {
+	var rec Recommendation
+	rec.ScoreBreakdown = make(map[string]float64)
+	rec.VaryHeader = "Accept-Encoding"
+
+	if file == nil {
+		return rec, fmt.Errorf("file is nil")
+	}
+
+	sizeBytes := int64(0)
+	if fi, err := file.Stat(); err == nil {
+		sizeBytes = fi.Size()
+	}
+	sizeMB := float64(sizeBytes) / (1024.0 * 1024.0)
+
+	mimeStr, category := detectFileCategory(file)
+	rec.DetectedMime = mimeStr
+	rec.DetectedCategory = category
+
+	accepted := ParseAcceptEncoding(acceptEncoding)
+
+	if isAlreadyCompressed(mimeStr, category) {
+		rec.ContentEncoding = "identity"
+		rec.SkipCompression = true
+		rec.Reason = fmt.Sprintf("文件类型 %s 属于图像/视频/音频/归档类，按客户端偏好二次压缩也收益很小，强制 identity。", category)
+		return rec, nil
+	}
+
+	var candidates []string
+	for _, c := range httpCompCandidates {
+		if acceptsEncoding(accepted, c) {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) == 0 {
+		rec.ContentEncoding = "identity"
+		rec.Reason = "客户端 Accept-Encoding 未接受任何受支持的压缩编码，回退到 identity。"
+		return rec, nil
+	}
+
+	scores := make(map[string]float64, len(candidates))
+	for _, c := range candidates {
+		score := 0.1
+		switch c {
+		case "br":
+			if category == "text" && sizeMB < 1 {
+				score += 2.5 // 小体积文本：br 在合理 CPU 开销下压缩比最好
+			} else {
+				score += 0.8
+			}
+		case "zstd":
+			if category == "text" && sizeMB >= 1 {
+				score += 2.3 // 大文本：zstd 的编码速度明显优于 br，比率仍然不错
+			} else {
+				score += 1.0
+			}
+		case "gzip":
+			score += 1.5 // 通用兜底，几乎所有客户端/代理都支持
+		case "deflate":
+			score += 0.6 // 语义含糊（raw deflate vs zlib），只作为权重最低的选项
+		}
+		scores[c] = score
+		rec.ScoreBreakdown["http_"+c] = score
+	}
+
+	best := candidates[0]
+	bestScore := scores[best]
+	for _, c := range candidates {
+		if scores[c] > bestScore {
+			best = c
+			bestScore = scores[c]
+		}
+	}
+
+	rec.ContentEncoding = best
+	rec.Reason = fmt.Sprintf("客户端接受 %v，按内容类型 %s（%.2f MiB）选中 %s（分数 %.2f）。", candidates, category, sizeMB, best, bestScore)
+	return rec, nil
+}
@@ -0,0 +1,81 @@
+package eca_recommend
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptEncoding(t *testing.T) {
+	cases := []struct {
+		name   string
+		header string
+		want   []Encoding
+	}{
+		{
+			name:   "empty header",
+			header: "",
+			want:   nil,
+		},
+		{
+			name:   "simple no q-values, input order preserved for ties",
+			header: "gzip, deflate",
+			want:   []Encoding{{Name: "gzip", Q: 1}, {Name: "deflate", Q: 1}},
+		},
+		{
+			name:   "sorted by q descending",
+			header: "gzip;q=0.5, br;q=0.9, deflate;q=0.1",
+			want:   []Encoding{{Name: "br", Q: 0.9}, {Name: "gzip", Q: 0.5}, {Name: "deflate", Q: 0.1}},
+		},
+		{
+			name:   "wildcard with explicit refusal",
+			header: "gzip, *;q=0",
+			want:   []Encoding{{Name: "gzip", Q: 1}, {Name: "*", Q: 0}},
+		},
+		{
+			name:   "duplicate tokens keep first occurrence",
+			header: "gzip;q=0.8, gzip;q=0.2",
+			want:   []Encoding{{Name: "gzip", Q: 0.8}},
+		},
+		{
+			name:   "identity explicitly refused",
+			header: "identity;q=0, gzip",
+			want:   []Encoding{{Name: "gzip", Q: 1}, {Name: "identity", Q: 0}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ParseAcceptEncoding(tc.header)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("ParseAcceptEncoding(%q) = %#v, want %#v", tc.header, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	cases := []struct {
+		name     string
+		header   string
+		encoding string
+		want     bool
+	}{
+		{"explicitly listed", "gzip, br", "br", true},
+		{"not listed, no wildcard", "gzip", "br", false},
+		{"wildcard allows anything not listed", "gzip, *;q=0.3", "br", true},
+		{"explicit q=0 refusal wins over listing", "br;q=0", "br", false},
+		{"wildcard refusal blocks unlisted", "gzip, *;q=0", "br", false},
+		{"identity allowed by default", "gzip", "identity", true},
+		{"identity can be explicitly refused", "identity;q=0, gzip", "identity", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			accepted := ParseAcceptEncoding(tc.header)
+			got := acceptsEncoding(accepted, tc.encoding)
+			if got != tc.want {
+				t.Fatalf("acceptsEncoding(%q, %q) = %v, want %v", tc.header, tc.encoding, got, tc.want)
+			}
+		})
+	}
+}
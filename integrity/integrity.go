@@ -0,0 +1,121 @@
+// Package integrity 提供纠删码/完整性方案的建议，是对 eca_recommend 加密压缩建议的补充：
+// 它只给出方案（JSON 可序列化），不做实际编码，真正的 RS 编码交给调用方接入
+// klauspost/reedsolomon 或 infectious 之类的库。
+package integrity
+
+import "fmt"
+
+// IntegrityPlan 描述针对单个文件建议采用的纠删码/校验方案。
+type IntegrityPlan struct {
+	Scheme             string // "none"|"crc32c"|"rs10+4"（DataShards+ParityShards 的简写）
+	DataShards         int    // RS 方案的数据分片数，非 RS 方案为 0
+	ParityShards       int    // RS 方案的校验分片数，非 RS 方案为 0
+	ChunkBytes         int    // 每个分片/校验块覆盖的字节数
+	HeaderRedundancy   int    // 关键元数据（header）的复制份数，1 表示不做额外复制
+	InterleaveWithAEAD bool   // 是否建议把 AEAD 分块与校验分片交错，避免单个坏分片拖垮整块认证
+	Reason             string
+}
+
+// 冷热、高低关注度的判定边界：冷热按 lastUsedHours（小时）计，关注度取值 [0, 1]。
+const (
+	coldHoursThreshold    = 24 * 30 // 30 天未使用视为"冷"
+	hotHoursThreshold     = 24 * 3  // 3 天内使用过视为"热"
+	highAttentionThresh   = 0.66
+	lowAttentionThreshold = 0.33
+)
+
+// Plan 根据文件的冷热程度、关注度和大小，给出一套纠删码/校验建议。
+// cold + high-attention（长期冷存但很重要）给最强的 RS(10,4) 保护；
+// hot + low-attention（热数据、不太重要）不建议额外开销；
+// 其余情况给一个轻量的 CRC32C 分块校验。
+func Plan(lastUsedHours int, attention float64, sizeBytes int64) IntegrityPlan {
+	if lastUsedHours < 0 {
+		lastUsedHours = 0
+	}
+	if attention < 0 {
+		attention = 0
+	} else if attention > 1 {
+		attention = 1
+	}
+	if sizeBytes < 0 {
+		sizeBytes = 0
+	}
+
+	cold := lastUsedHours >= coldHoursThreshold
+	hot := lastUsedHours <= hotHoursThreshold
+	highAttention := attention >= highAttentionThresh
+	lowAttention := attention <= lowAttentionThreshold
+
+	var plan IntegrityPlan
+	switch {
+	case cold && highAttention:
+		plan = IntegrityPlan{
+			Scheme:             "rs10+4",
+			DataShards:         10,
+			ParityShards:       4,
+			ChunkBytes:         SuggestChunkSize(sizeBytes, "ratio"),
+			HeaderRedundancy:   3,
+			InterleaveWithAEAD: true,
+			Reason:             "长期冷存且高关注度：用 RS(10,4) 保护数据分片，header 三副本，AEAD 分块与校验分片交错以隔离坏块。",
+		}
+	case hot && lowAttention:
+		plan = IntegrityPlan{
+			Scheme:             "none",
+			HeaderRedundancy:   1,
+			InterleaveWithAEAD: false,
+			Reason:             "近期频繁访问且关注度低：额外纠错的收益不值得付出的存储/CPU 开销。",
+		}
+	default:
+		plan = IntegrityPlan{
+			Scheme:             "crc32c",
+			ChunkBytes:         SuggestChunkSize(sizeBytes, "balanced"),
+			HeaderRedundancy:   2,
+			InterleaveWithAEAD: false,
+			Reason:             "介于冷热/关注度之间：只做按块 CRC32C 校验以便发现静默损坏，不引入纠删码的额外冗余。",
+		}
+	}
+	return plan
+}
+
+// SuggestChunkSize 在 64 KiB 到 16 MiB 之间，按文件大小和 tradeoff（"speed"|"ratio"|"balanced"）
+// 给出一个分块大小：越偏向 ratio 越倾向更大的块（摊薄每块的 header 开销），越偏向 speed
+// 越倾向更小的块（降低单块重试/重传的延迟代价）。
+func SuggestChunkSize(sizeBytes int64, tradeoff string) int {
+	const (
+		minChunk = 64 * 1024
+		maxChunk = 16 * 1024 * 1024
+	)
+	if sizeBytes <= 0 {
+		return minChunk
+	}
+
+	var chunk int
+	switch tradeoff {
+	case "speed":
+		chunk = 256 * 1024
+	case "ratio":
+		chunk = 4 * 1024 * 1024
+	default: // "balanced" 及其它未知值
+		chunk = 1 * 1024 * 1024
+	}
+
+	// 小文件没必要用比自身大很多的块。
+	for int64(chunk) > sizeBytes && chunk > minChunk {
+		chunk /= 2
+	}
+	if chunk < minChunk {
+		chunk = minChunk
+	}
+	if chunk > maxChunk {
+		chunk = maxChunk
+	}
+	return chunk
+}
+
+// String 实现 fmt.Stringer，便于日志/调试输出。
+func (p IntegrityPlan) String() string {
+	if p.Scheme == "rs10+4" {
+		return fmt.Sprintf("%s chunk=%dB header_redundancy=%d interleave=%v", p.Scheme, p.ChunkBytes, p.HeaderRedundancy, p.InterleaveWithAEAD)
+	}
+	return fmt.Sprintf("%s chunk=%dB header_redundancy=%d", p.Scheme, p.ChunkBytes, p.HeaderRedundancy)
+}
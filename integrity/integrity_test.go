@@ -0,0 +1,108 @@
+package integrity
+
+import "testing"
+
+func TestPlan(t *testing.T) {
+	const mib = 1024 * 1024
+
+	cases := []struct {
+		name          string
+		lastUsedHours int
+		attention     float64
+		sizeBytes     int64
+		wantScheme    string
+		wantChunk     int
+	}{
+		{
+			name:          "cold and high attention gets RS protection",
+			lastUsedHours: 24 * 60, // 60 天未使用
+			attention:     0.9,
+			sizeBytes:     500 * mib,
+			wantScheme:    "rs10+4",
+			wantChunk:     SuggestChunkSize(500*mib, "ratio"),
+		},
+		{
+			name:          "hot and low attention gets no ECC",
+			lastUsedHours: 1,
+			attention:     0.1,
+			sizeBytes:     10 * mib,
+			wantScheme:    "none",
+			wantChunk:     0,
+		},
+		{
+			name:          "in-between gets crc32c only",
+			lastUsedHours: 24 * 10, // 既不够冷也不够热
+			attention:     0.5,
+			sizeBytes:     200 * mib,
+			wantScheme:    "crc32c",
+			wantChunk:     SuggestChunkSize(200*mib, "balanced"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := Plan(tc.lastUsedHours, tc.attention, tc.sizeBytes)
+			if plan.Scheme != tc.wantScheme {
+				t.Fatalf("Scheme = %q, want %q", plan.Scheme, tc.wantScheme)
+			}
+			if plan.ChunkBytes != tc.wantChunk {
+				t.Fatalf("ChunkBytes = %d, want %d", plan.ChunkBytes, tc.wantChunk)
+			}
+		})
+	}
+}
+
+func TestPlan_RSBranchDetail(t *testing.T) {
+	plan := Plan(24*60, 0.9, 500*1024*1024)
+	if plan.DataShards != 10 || plan.ParityShards != 4 {
+		t.Fatalf("DataShards/ParityShards = %d/%d, want 10/4", plan.DataShards, plan.ParityShards)
+	}
+	if plan.HeaderRedundancy != 3 {
+		t.Fatalf("HeaderRedundancy = %d, want 3", plan.HeaderRedundancy)
+	}
+	if !plan.InterleaveWithAEAD {
+		t.Fatalf("InterleaveWithAEAD = false, want true for cold+high-attention")
+	}
+}
+
+func TestPlan_ClampsInputs(t *testing.T) {
+	// 负数输入应当被夹到合法范围内，而不是让冷/热、高/低关注度判断产生意外结果。
+	plan := Plan(-5, -1, -100)
+	if plan.Scheme != "none" {
+		t.Fatalf("Scheme = %q, want %q for clamped (hot, low-attention) inputs", plan.Scheme, "none")
+	}
+}
+
+func TestSuggestChunkSize(t *testing.T) {
+	const (
+		minChunk = 64 * 1024
+		maxChunk = 16 * 1024 * 1024
+	)
+
+	cases := []struct {
+		name      string
+		sizeBytes int64
+		tradeoff  string
+		want      int
+	}{
+		{"zero size falls back to min chunk", 0, "balanced", minChunk},
+		{"negative size falls back to min chunk", -1, "ratio", minChunk},
+		{"speed tradeoff picks 256 KiB for a big file", 1024 * 1024 * 1024, "speed", 256 * 1024},
+		{"ratio tradeoff picks 4 MiB for a big file", 1024 * 1024 * 1024, "ratio", 4 * 1024 * 1024},
+		{"balanced/unknown tradeoff picks 1 MiB for a big file", 1024 * 1024 * 1024, "unknown-tradeoff", 1024 * 1024},
+		{"small file halves down below the tradeoff's default chunk", 100 * 1024, "ratio", 64 * 1024},
+		{"halving never drops below the 64 KiB floor", 1024, "speed", minChunk},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := SuggestChunkSize(tc.sizeBytes, tc.tradeoff)
+			if got != tc.want {
+				t.Fatalf("SuggestChunkSize(%d, %q) = %d, want %d", tc.sizeBytes, tc.tradeoff, got, tc.want)
+			}
+			if got < minChunk || got > maxChunk {
+				t.Fatalf("SuggestChunkSize(%d, %q) = %d, out of [%d, %d] bounds", tc.sizeBytes, tc.tradeoff, got, minChunk, maxChunk)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package eca_recommend
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SolidBlock 描述一组被打包进同一个压缩块的文件：同一个 codec/level 作为整体压缩，
+// 以便小文件之间能共享字典/上下文，获得比逐个压缩更好的比率。
+type SolidBlock struct {
+	Files []string
+	Codec string
+	Level int
+}
+
+// TreePlan 是 RecommendForTree 给出的整棵目录树的打包方案：单一的加密算法、
+// 若干个分组压缩块，以及建议的归档容器。
+type TreePlan struct {
+	Encryption      string
+	Container       string // "tar+zstd"|"zip-store"|"7z"
+	SolidBlocks     []SolidBlock
+	Recommendations map[string]Recommendation // key 为 bucket 名（category/sizeClass），值为该 bucket 的代表性推荐
+	TotalBytes      int64
+	EstimatedRatio  float64 // 基于采样子集估算的整体压缩比（压缩后/压缩前）
+	Reason          string
+}
+
+// sizeClass 把文件按字节数划成三档，用于分桶。
+func sizeClass(sizeBytes int64) string {
+	const mib = 1024 * 1024
+	switch {
+	case sizeBytes < mib:
+		return "small"
+	case sizeBytes < 100*mib:
+		return "medium"
+	default:
+		return "large"
+	}
+}
+
+// treeBucket 累积同一个 (category, sizeClass) 桶内的文件信息，用于加权投票和分组。
+type treeBucket struct {
+	files      []string
+	totalBytes int64
+	rec        Recommendation // 桶内最后一次成功评分的代表性 Recommendation
+}
+
+// RecommendForTree 遍历 root 目录下的所有常规文件，沿用 detectFileCategory 的检测逻辑，
+// 按 (category, sizeClass) 分桶，并把各桶的单文件评分聚合成一份整树打包方案：
+// 加密算法由各文件选中的 bestEnc 投票决定，每票按该文件字节数加权；压缩按"已压缩文件单独 stored"、
+// "小文本文件合并进一个 solid LZMA2 块"、"其余走流式 zstd" 的规则分组。
+func RecommendForTree(root string, prefs Prefs) (TreePlan, error) {
+	var plan TreePlan
+	buckets := make(map[string]*treeBucket)
+
+	encVotes := map[string]float64{}
+	var alreadyCompressedFiles []string
+	var smallTextFiles []string
+	var streamFiles []string
+	var sampledRatios []float64
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		lastUsedHours := int(time.Since(info.ModTime()).Hours())
+		// 没有单文件访问热度数据时，假设中等关注度，避免把整棵树的加密选择推向极端。
+		attention := 0.5
+
+		rec, err := RecommendAlgorithms(f, lastUsedHours, attention, info.Size(), prefs)
+		if err != nil {
+			return nil // 单个文件评分失败不应该中断整棵树的遍历
+		}
+
+		plan.TotalBytes += info.Size()
+		encVotes[rec.Encryption] += float64(info.Size())
+
+		key := rec.DetectedCategory + "/" + sizeClass(info.Size())
+		b, ok := buckets[key]
+		if !ok {
+			b = &treeBucket{}
+			buckets[key] = b
+		}
+		b.files = append(b.files, path)
+		b.totalBytes += info.Size()
+		b.rec = rec
+
+		switch {
+		case rec.SkipCompression || isAlreadyCompressed(rec.DetectedMime, rec.DetectedCategory):
+			alreadyCompressedFiles = append(alreadyCompressedFiles, path)
+		case rec.DetectedCategory == "text" && info.Size() < 1*1024*1024:
+			smallTextFiles = append(smallTextFiles, path)
+		default:
+			streamFiles = append(streamFiles, path)
+		}
+
+		if len(sampledRatios) < 32 {
+			if _, trialRatio, perr := EstimateCompressibility(f, 64*1024); perr == nil {
+				sampledRatios = append(sampledRatios, trialRatio)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return plan, fmt.Errorf("walk tree: %w", err)
+	}
+
+	plan.Recommendations = make(map[string]Recommendation, len(buckets))
+	for key, b := range buckets {
+		plan.Recommendations[key] = b.rec
+	}
+
+	// ---------- 加密算法：按字节数加权投票 ----------
+	bestEnc := ""
+	bestVotes := -1.0
+	for enc, votes := range encVotes {
+		if votes > bestVotes {
+			bestVotes = votes
+			bestEnc = enc
+		}
+	}
+	if bestEnc == "" {
+		bestEnc = "aes256gcm"
+	}
+	plan.Encryption = bestEnc
+
+	// ---------- 分组压缩 ----------
+	if len(alreadyCompressedFiles) > 0 {
+		plan.SolidBlocks = append(plan.SolidBlocks, SolidBlock{
+			Files: alreadyCompressedFiles,
+			Codec: "store",
+			Level: 0,
+		})
+	}
+	if len(smallTextFiles) > 0 {
+		plan.SolidBlocks = append(plan.SolidBlocks, SolidBlock{
+			Files: smallTextFiles,
+			Codec: "lzma2",
+			Level: 9, // solid 块优先追求比率：块内文件共享上下文，体积通常不大，CPU 成本可接受
+		})
+	}
+	if len(streamFiles) > 0 {
+		streamBytes := int64(0)
+		for _, fpath := range streamFiles {
+			if fi, ferr := os.Stat(fpath); ferr == nil {
+				streamBytes += fi.Size()
+			}
+		}
+		level := 3
+		streamMB := float64(streamBytes) / (1024.0 * 1024.0)
+		if streamMB > 500 {
+			level = 9
+		} else if streamMB > 100 {
+			level = 5
+		}
+		plan.SolidBlocks = append(plan.SolidBlocks, SolidBlock{
+			Files: streamFiles,
+			Codec: "zstd",
+			Level: level,
+		})
+	}
+
+	// ---------- 归档容器 ----------
+	switch {
+	case len(alreadyCompressedFiles) > 0 && len(streamFiles) == 0 && len(smallTextFiles) == 0:
+		plan.Container = "zip-store"
+	case len(smallTextFiles) > 0 && len(streamFiles) == 0:
+		plan.Container = "7z"
+	default:
+		plan.Container = "tar+zstd"
+	}
+
+	// ---------- 估算整体比率 ----------
+	if len(sampledRatios) > 0 {
+		sum := 0.0
+		for _, r := range sampledRatios {
+			sum += r
+		}
+		plan.EstimatedRatio = sum / float64(len(sampledRatios))
+	}
+
+	plan.Reason = fmt.Sprintf("扫描到 %d 个文件共 %.2f MiB，加密选 %s（按字节加权投票），容器建议 %s，分出 %d 个 solid 压缩块。",
+		len(alreadyCompressedFiles)+len(smallTextFiles)+len(streamFiles), float64(plan.TotalBytes)/(1024.0*1024.0), plan.Encryption, plan.Container, len(plan.SolidBlocks))
+
+	return plan, nil
+}
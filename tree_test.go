@@ -0,0 +1,158 @@
+package eca_recommend
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// treeTestPrefs disables the entropy/trial-compression probe so bucket assignment in
+// these tests depends only on the (deterministic) MIME/category detection, not on
+// pseudo-random sample offsets or flate's behavior on the fixture content.
+var treeTestPrefs = Prefs{DisableCompressibilityProbe: true}
+
+func writeFixture(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("WriteFile(%q): %v", path, err)
+	}
+	return path
+}
+
+func TestRecommendForTree_AllAlreadyCompressed_PicksZipStore(t *testing.T) {
+	dir := t.TempDir()
+	gzipMagic := []byte{0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00}
+	writeFixture(t, dir, "a.gz", append(append([]byte{}, gzipMagic...), bytes.Repeat([]byte{1, 2, 3}, 100)...))
+	writeFixture(t, dir, "b.gz", append(append([]byte{}, gzipMagic...), bytes.Repeat([]byte{4, 5, 6}, 100)...))
+
+	plan, err := RecommendForTree(dir, treeTestPrefs)
+	if err != nil {
+		t.Fatalf("RecommendForTree: %v", err)
+	}
+
+	if plan.Container != "zip-store" {
+		t.Fatalf("Container = %q, want zip-store", plan.Container)
+	}
+	if len(plan.SolidBlocks) != 1 {
+		t.Fatalf("SolidBlocks = %#v, want exactly one block", plan.SolidBlocks)
+	}
+	if plan.SolidBlocks[0].Codec != "store" {
+		t.Fatalf("SolidBlocks[0].Codec = %q, want store", plan.SolidBlocks[0].Codec)
+	}
+	if len(plan.SolidBlocks[0].Files) != 2 {
+		t.Fatalf("SolidBlocks[0].Files = %v, want 2 files", plan.SolidBlocks[0].Files)
+	}
+}
+
+func TestRecommendForTree_AllSmallText_Picks7z(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "notes.txt", []byte("these are some plain english notes about the project\n"))
+	writeFixture(t, dir, "log.txt", []byte("2026-07-26 started service\n2026-07-26 stopped service\n"))
+
+	plan, err := RecommendForTree(dir, treeTestPrefs)
+	if err != nil {
+		t.Fatalf("RecommendForTree: %v", err)
+	}
+
+	if plan.Container != "7z" {
+		t.Fatalf("Container = %q, want 7z", plan.Container)
+	}
+	if len(plan.SolidBlocks) != 1 {
+		t.Fatalf("SolidBlocks = %#v, want exactly one block", plan.SolidBlocks)
+	}
+	if plan.SolidBlocks[0].Codec != "lzma2" {
+		t.Fatalf("SolidBlocks[0].Codec = %q, want lzma2", plan.SolidBlocks[0].Codec)
+	}
+}
+
+func TestRecommendForTree_MixedContent_PicksTarZstd(t *testing.T) {
+	dir := t.TempDir()
+	gzipMagic := []byte{0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00}
+	writeFixture(t, dir, "archive.gz", append(append([]byte{}, gzipMagic...), bytes.Repeat([]byte{9, 9, 9}, 50)...))
+	writeFixture(t, dir, "notes.txt", []byte("a small text file that should be grouped into a solid block\n"))
+	// Random bytes don't match any registered signature and don't look like valid
+	// text to http.DetectContentType, so they fall through to the "stream" bucket.
+	randomBlob := make([]byte, 4096)
+	if _, err := rand.Read(randomBlob); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	writeFixture(t, dir, "blob.bin", randomBlob)
+
+	plan, err := RecommendForTree(dir, treeTestPrefs)
+	if err != nil {
+		t.Fatalf("RecommendForTree: %v", err)
+	}
+
+	if plan.Container != "tar+zstd" {
+		t.Fatalf("Container = %q, want tar+zstd", plan.Container)
+	}
+
+	gotCodecs := map[string]bool{}
+	for _, b := range plan.SolidBlocks {
+		gotCodecs[b.Codec] = true
+	}
+	for _, codec := range []string{"store", "lzma2", "zstd"} {
+		if !gotCodecs[codec] {
+			t.Fatalf("SolidBlocks = %#v, missing a %q block", plan.SolidBlocks, codec)
+		}
+	}
+}
+
+func TestRecommendForTree_EncryptionVoteFollowsByteWeight(t *testing.T) {
+	dir := t.TempDir()
+
+	smallPath := writeFixture(t, dir, "small.bin", bytes.Repeat([]byte{0x01}, 1024))
+
+	// A sparse file: Stat().Size() reports the real size without us having to write
+	// 100+ MiB of actual content, which is what pushes the per-file encryption score
+	// towards xchacha20poly1305 (the scorer's >100 MiB bonus).
+	bigPath := filepath.Join(dir, "big.bin")
+	bigFile, err := os.Create(bigPath)
+	if err != nil {
+		t.Fatalf("Create(big.bin): %v", err)
+	}
+	const bigSize = 101 * 1024 * 1024
+	if err := bigFile.Truncate(bigSize); err != nil {
+		bigFile.Close()
+		t.Fatalf("Truncate: %v", err)
+	}
+	bigFile.Close()
+
+	plan, err := RecommendForTree(dir, treeTestPrefs)
+	if err != nil {
+		t.Fatalf("RecommendForTree: %v", err)
+	}
+
+	// Sanity-check the scenario still holds: the single >100 MiB file must dominate
+	// the byte-weighted vote over the 1 KiB file, regardless of which encryption
+	// algorithm the per-file scorer currently prefers for that size class.
+	bigFileHandle, err := os.Open(bigPath)
+	if err != nil {
+		t.Fatalf("Open(big.bin): %v", err)
+	}
+	defer bigFileHandle.Close()
+	bigRec, err := RecommendAlgorithms(bigFileHandle, 0, 0.5, bigSize, treeTestPrefs)
+	if err != nil {
+		t.Fatalf("RecommendAlgorithms(big): %v", err)
+	}
+
+	if plan.Encryption != bigRec.Encryption {
+		t.Fatalf("Encryption = %q, want %q (the >100 MiB file's pick, which should dominate the 1 KiB file's vote)", plan.Encryption, bigRec.Encryption)
+	}
+
+	if plan.TotalBytes != bigSize+int64(len(mustReadAll(t, smallPath))) {
+		t.Fatalf("TotalBytes = %d, want sum of both file sizes", plan.TotalBytes)
+	}
+}
+
+func mustReadAll(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%q): %v", path, err)
+	}
+	return data
+}